@@ -9,6 +9,7 @@ import (
 	_ "github.com/mxk/go-sqlite/sqlite3"
 	"github.com/rwcarlsen/optim"
 	"github.com/rwcarlsen/optim/bench"
+	"github.com/rwcarlsen/optim/cmaes"
 	"github.com/rwcarlsen/optim/mesh"
 	"github.com/rwcarlsen/optim/pattern"
 	"github.com/rwcarlsen/optim/swarm"
@@ -111,6 +112,95 @@ func TestBenchPSwarmRastrigrin(t *testing.T) {
 	bench.Benchmark(t, fn, sfn, successfrac, avgiter)
 }
 
+func TestBenchCmaesRastrigrin(t *testing.T) {
+	ndim := 30
+	maxiter := 10000
+	// Rastrigrin at n=30 is highly multimodal; CMA-ES's default population
+	// (lambda = 4+floor(3*ln(n)) = 14, no restarts) is sized for local
+	// convergence speed, not global coverage, so it won't reliably escape
+	// every basin. Widen the population some to improve odds of finding
+	// the global optimum and budget more generations accordingly, but
+	// still expect (and accept) a meaningful failure rate rather than
+	// asserting the 100%/150-iteration thresholds of
+	// TestBenchPSwarmRastrigrin.
+	lambda := 4 * (4 + int(3*math.Log(float64(ndim))))
+	successfrac := 0.60
+	avgiter := 1500.0
+
+	fn := bench.Rastrigrin{ndim}
+	sfn := func() *optim.Solver {
+		p := initialpoint(fn)
+		return &optim.Solver{
+			Method:  cmaes.New(p, func(m *cmaes.Method) { m.Lambda = lambda }),
+			Obj:     optim.Func(fn.Eval),
+			MaxEval: maxiter * ndim,
+			MaxIter: maxiter,
+		}
+	}
+	bench.Benchmark(t, fn, sfn, successfrac, avgiter)
+}
+
+func TestBenchCmaesGriewank(t *testing.T) {
+	ndim := 30
+	maxiter := 10000
+	// see TestBenchCmaesRastrigrin: Griewank at n=30 is likewise
+	// multimodal, though its basins are shallower, so give it the same
+	// widened population but expect a somewhat better success rate.
+	lambda := 4 * (4 + int(3*math.Log(float64(ndim))))
+	successfrac := 0.75
+	avgiter := 1200.0
+
+	fn := bench.Griewank{ndim}
+	sfn := func() *optim.Solver {
+		p := initialpoint(fn)
+		return &optim.Solver{
+			Method:  cmaes.New(p, func(m *cmaes.Method) { m.Lambda = lambda }),
+			Obj:     optim.Func(fn.Eval),
+			MaxEval: maxiter * ndim,
+			MaxIter: maxiter,
+		}
+	}
+	bench.Benchmark(t, fn, sfn, successfrac, avgiter)
+}
+
+func TestBenchModelSearchRosen(t *testing.T) {
+	ndim := 30
+	maxiter := 10000
+	// ModelSearch's trust-region step is one candidate per outer
+	// iteration (on top of the underlying poll step), so it needs
+	// meaningfully more iterations than a population-based method like
+	// PSwarm to work through Rosenbrock's curved valley at n=30; these
+	// numbers replace ones that were copied from the unrelated PSwarm
+	// Griewank/Rastrigrin cases rather than tuned for this method.
+	successfrac := 0.90
+	avgiter := 3000.0
+
+	fn := bench.Rosenbrock{ndim}
+	sfn := func() *optim.Solver {
+		low, up := fn.Bounds()
+		max, min := up[0], low[0]
+		m := &mesh.Infinite{StepSize: (max - min) / 10}
+		p := initialpoint(fn)
+		m.SetOrigin(p.Pos())
+
+		it := pattern.New(p,
+			pattern.SearchMethod(&pattern.ModelSearch{Delta0: (max - min) / 10}, pattern.Share),
+		)
+		it.Poller = &pattern.Poller{
+			SkipEps: 1e-10,
+			SpanFn:  pattern.CompassNp1,
+		}
+		return &optim.Solver{
+			Method:  it,
+			Obj:     optim.Func(fn.Eval),
+			Mesh:    m,
+			MaxEval: maxiter * ndim,
+			MaxIter: maxiter,
+		}
+	}
+	bench.Benchmark(t, fn, sfn, successfrac, avgiter)
+}
+
 func TestOverviewPattern(t *testing.T) {
 	maxeval := 50000
 	maxiter := 5000
@@ -0,0 +1,308 @@
+// Package cmaes implements Hansen's Covariance Matrix Adaptation Evolution
+// Strategy (CMA-ES) as an optim.Iterator, so it plugs into the same drivers
+// as swarm and pattern.
+package cmaes
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/rwcarlsen/optim"
+	"github.com/rwcarlsen/optim/mesh"
+)
+
+// Method is a CMA-ES optim.Iterator.  Each iteration it samples Lambda
+// points x_i = mean + sigma*B*D*z_i (z_i ~ N(0,I)) via Ev, selects the Mu
+// best to update the mean and covariance C = B*D^2*B^T, evolves the
+// isotropic and anisotropic evolution paths, and adapts the step size
+// sigma.
+type Method struct {
+	// Lambda is the population size sampled each iteration.  If zero, it
+	// defaults to 4 + floor(3*ln(n)).
+	Lambda int
+	// Mu is the number of points used to update the mean and covariance.
+	// If zero, it defaults to Lambda/2.
+	Mu int
+	// Sigma0 is the initial step size. If zero, 1 is used.
+	Sigma0 float64
+	// Ev evaluates sampled points.  If nil, optim.SerialEvaler{} is used.
+	Ev optim.Evaler
+	// Rand supplies randomness for sampling.  If nil, a source seeded
+	// with a fixed value is used so runs are reproducible by default.
+	Rand *rand.Rand
+
+	n     int
+	sigma float64
+	mean  []float64
+	ps    []float64
+	pc    []float64
+	c     [][]float64
+	b     [][]float64
+	d     []float64
+	iter  int
+	eigAt int // iteration B/D were last recomputed (from C) at
+
+	weights []float64
+	mueff   float64
+	cs, ds  float64
+	cc      float64
+	c1, cmu float64
+	chiN    float64
+
+	best optim.Point
+}
+
+// New creates a CMA-ES Method seeded at initial's position.
+func New(initial optim.Point, opts ...func(*Method)) *Method {
+	m := &Method{best: initial}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *Method) init(n int) {
+	m.n = n
+
+	if m.Lambda == 0 {
+		m.Lambda = 4 + int(3*math.Log(float64(n)))
+	}
+	if m.Mu == 0 {
+		m.Mu = m.Lambda / 2
+	}
+	if m.Sigma0 == 0 {
+		m.Sigma0 = 1
+	}
+	if m.Ev == nil {
+		m.Ev = optim.SerialEvaler{}
+	}
+	if m.Rand == nil {
+		m.Rand = rand.New(rand.NewSource(1))
+	}
+
+	m.sigma = m.Sigma0
+	m.mean = m.best.Pos()
+	m.ps = make([]float64, n)
+	m.pc = make([]float64, n)
+	m.c = identity(n)
+	m.b = identity(n)
+	m.d = make([]float64, n)
+	for i := range m.d {
+		m.d[i] = 1
+	}
+
+	// w_i ∝ log(mu+1) - log(i), i=1..mu, normalized to sum to 1.
+	m.weights = make([]float64, m.Mu)
+	wsum := 0.0
+	for i := 0; i < m.Mu; i++ {
+		w := math.Log(float64(m.Mu)+1) - math.Log(float64(i+1))
+		m.weights[i] = w
+		wsum += w
+	}
+	sqsum := 0.0
+	for i := range m.weights {
+		m.weights[i] /= wsum
+		sqsum += m.weights[i] * m.weights[i]
+	}
+	m.mueff = 1 / sqsum
+
+	nf := float64(n)
+	m.cc = (4 + m.mueff/nf) / (nf + 4 + 2*m.mueff/nf)
+	m.cs = (m.mueff + 2) / (nf + m.mueff + 5)
+	m.c1 = 2 / (sq(nf+1.3) + m.mueff)
+	m.cmu = math.Min(1-m.c1, 2*(m.mueff-2+1/m.mueff)/(sq(nf+2)+m.mueff))
+	m.ds = 1 + 2*math.Max(0, math.Sqrt((m.mueff-1)/(nf+1))-1) + m.cs
+	m.chiN = math.Sqrt(nf) * (1 - 1/(4*nf) + 1/(21*nf*nf))
+}
+
+// AddPoint updates m's notion of the best point found so far.
+func (m *Method) AddPoint(p optim.Point) {
+	if p.Val < m.best.Val {
+		m.best = p
+	}
+}
+
+// Iterate samples and evaluates one generation of Lambda points and updates
+// the search distribution from the Mu best of them.
+func (m *Method) Iterate(ctx context.Context, obj optim.Objectiver, msh mesh.Mesh) (best optim.Point, n int, err error) {
+	if m.n == 0 {
+		m.init(m.best.Len())
+	}
+
+	if m.iter-m.eigAt >= max(1, m.n/10) {
+		m.updateEig()
+	}
+
+	pts := make([]optim.Point, m.Lambda)
+	zs := make([][]float64, m.Lambda)
+	for i := 0; i < m.Lambda; i++ {
+		z := m.sampleNormal()
+		zs[i] = z
+		bdz := m.bd(z)
+		pos := make([]float64, m.n)
+		for j := range pos {
+			pos[j] = m.mean[j] + m.sigma*bdz[j]
+		}
+		p := optim.NewPoint(pos, math.Inf(1))
+		if msh != nil {
+			p = optim.Nearest(p, msh)
+		}
+		pts[i] = p
+	}
+
+	results, ne, err := m.Ev.Eval(ctx, obj, pts...)
+	n += ne
+	if err != nil {
+		return m.best, n, err
+	}
+	for _, p := range results {
+		m.AddPoint(p)
+	}
+
+	sortByVal(results, zs[:len(results)])
+	if len(results) < m.Mu {
+		// too few successful evaluations this generation to update the
+		// distribution meaningfully; just report progress so far.
+		return m.best, n, nil
+	}
+
+	m.update(zs)
+	m.iter++
+
+	return m.best, n, nil
+}
+
+// update performs the mean/path/covariance/step-size adaptation from the Mu
+// best z samples of a generation (zs must already be sorted by objective
+// value, best first).
+func (m *Method) update(zs [][]float64) {
+	n := m.n
+
+	zmean := make([]float64, n)
+	for i := 0; i < m.Mu; i++ {
+		for j := 0; j < n; j++ {
+			zmean[j] += m.weights[i] * zs[i][j]
+		}
+	}
+	bdzmean := m.bd(zmean)
+
+	newMean := make([]float64, n)
+	for j := 0; j < n; j++ {
+		newMean[j] = m.mean[j] + m.sigma*bdzmean[j]
+	}
+
+	// isotropic evolution path / step-size control.
+	bzmean := m.bmul(zmean)
+	for j := range m.ps {
+		m.ps[j] = (1-m.cs)*m.ps[j] + math.Sqrt(m.cs*(2-m.cs)*m.mueff)*bzmean[j]
+	}
+	psnorm := norm(m.ps)
+	m.sigma *= math.Exp((m.cs / m.ds) * (psnorm/m.chiN - 1))
+
+	// anisotropic evolution path.
+	hsig := 0.0
+	if psnorm/math.Sqrt(1-math.Pow(1-m.cs, 2*float64(m.iter+1)))/m.chiN < 1.4+2/(float64(n)+1) {
+		hsig = 1
+	}
+	for j := range m.pc {
+		m.pc[j] = (1-m.cc)*m.pc[j] + hsig*math.Sqrt(m.cc*(2-m.cc)*m.mueff)*bdzmean[j]
+	}
+
+	// covariance update: rank-1 (pc*pc^T) + rank-mu (sum w_i*(B*D*z_i)(B*D*z_i)^T).
+	bdzs := make([][]float64, m.Mu)
+	for i := 0; i < m.Mu; i++ {
+		bdzs[i] = m.bd(zs[i])
+	}
+	for r := 0; r < n; r++ {
+		for col := 0; col < n; col++ {
+			rank1 := m.pc[r] * m.pc[col]
+			rankmu := 0.0
+			for i := 0; i < m.Mu; i++ {
+				rankmu += m.weights[i] * bdzs[i][r] * bdzs[i][col]
+			}
+			m.c[r][col] = (1-m.c1-m.cmu)*m.c[r][col] + m.c1*rank1 + m.cmu*rankmu
+		}
+	}
+
+	m.mean = newMean
+}
+
+// bd returns B*D*z.
+func (m *Method) bd(z []float64) []float64 {
+	dz := make([]float64, len(z))
+	for i := range z {
+		dz[i] = m.d[i] * z[i]
+	}
+	return m.bmul(dz)
+}
+
+// bmul returns B*v.
+func (m *Method) bmul(v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i := range out {
+		for j := range v {
+			out[i] += m.b[i][j] * v[j]
+		}
+	}
+	return out
+}
+
+func (m *Method) sampleNormal() []float64 {
+	z := make([]float64, m.n)
+	for i := range z {
+		z[i] = m.Rand.NormFloat64()
+	}
+	return z
+}
+
+// updateEig recomputes B and D from the eigendecomposition of the
+// (symmetric) covariance matrix C, via the cyclic Jacobi method.
+func (m *Method) updateEig() {
+	vals, vecs := jacobiEigen(m.c)
+	for i, v := range vals {
+		if v < 0 {
+			v = 0
+		}
+		m.d[i] = math.Sqrt(v)
+	}
+	m.b = vecs
+	m.eigAt = m.iter
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+func sq(x float64) float64 { return x * x }
+
+func norm(v []float64) float64 {
+	tot := 0.0
+	for _, x := range v {
+		tot += x * x
+	}
+	return math.Sqrt(tot)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// sortByVal sorts results (and the zs used to produce them) ascending by
+// Val, in lockstep.
+func sortByVal(results []optim.Point, zs [][]float64) {
+	for i := 1; i < len(results); i++ {
+		for j := i; j > 0 && results[j].Val < results[j-1].Val; j-- {
+			results[j], results[j-1] = results[j-1], results[j]
+			zs[j], zs[j-1] = zs[j-1], zs[j]
+		}
+	}
+}
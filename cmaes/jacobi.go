@@ -0,0 +1,76 @@
+package cmaes
+
+import "math"
+
+// jacobiEigen computes the eigenvalues and eigenvectors of the symmetric
+// matrix a via the classic cyclic Jacobi rotation method.  It returns the
+// eigenvalues and a matrix whose columns are the corresponding
+// (orthonormal) eigenvectors.  a is not modified.
+func jacobiEigen(a [][]float64) (vals []float64, vecs [][]float64) {
+	n := len(a)
+
+	A := make([][]float64, n)
+	for i := range A {
+		A[i] = append([]float64(nil), a[i]...)
+	}
+
+	V := identity(n)
+
+	const maxSweeps = 100
+	for sweep := 0; sweep < maxSweeps; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += A[i][j] * A[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(A[p][q]) < 1e-300 {
+					continue
+				}
+
+				theta := (A[q][q] - A[p][p]) / (2 * A[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta == 0 {
+					t = 1
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				app, aqq, apq := A[p][p], A[q][q], A[p][q]
+				A[p][p] = c*c*app - 2*s*c*apq + s*s*aqq
+				A[q][q] = s*s*app + 2*s*c*apq + c*c*aqq
+				A[p][q] = 0
+				A[q][p] = 0
+
+				for i := 0; i < n; i++ {
+					if i == p || i == q {
+						continue
+					}
+					aip, aiq := A[i][p], A[i][q]
+					A[i][p] = c*aip - s*aiq
+					A[p][i] = A[i][p]
+					A[i][q] = s*aip + c*aiq
+					A[q][i] = A[i][q]
+				}
+
+				for i := 0; i < n; i++ {
+					vip, viq := V[i][p], V[i][q]
+					V[i][p] = c*vip - s*viq
+					V[i][q] = s*vip + c*viq
+				}
+			}
+		}
+	}
+
+	vals = make([]float64, n)
+	for i := range vals {
+		vals[i] = A[i][i]
+	}
+	return vals, V
+}
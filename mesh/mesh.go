@@ -0,0 +1,95 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Mesh defines a (potentially infinite) discrete set of candidate points
+// that an optimization method is restricted to.  Nearest snaps an arbitrary
+// point in continuous space to the closest point on the mesh.
+type Mesh interface {
+	Nearest(p []float64) []float64
+}
+
+// Infinite is a mesh with infinite extent: a regular grid with spacing
+// StepSize along the directions given by the columns of Basis (the standard
+// basis is used if Basis is nil), anchored at Origin (the zero vector is
+// used if Origin is nil).  If A, B (and/or E, F) are set, the mesh is
+// additionally restricted to the feasible region of the linear inequality
+// system Ax<=B (and/or the linear equality system Ex=F); Nearest snaps to
+// the unconstrained grid first and then, if necessary, projects onto that
+// feasible region via the active-set QP solver in Nearest (project.go).
+type Infinite struct {
+	StepSize float64
+	Origin   []float64
+	Basis    *mat64.Dense
+	A, B     *mat64.Dense
+	E, F     *mat64.Dense
+}
+
+// SetOrigin anchors the mesh's grid at p so that p itself is always a grid
+// point.  Pattern-search style methods rely on this to keep the mesh
+// centered on their current iterate.
+func (m *Infinite) SetOrigin(p []float64) {
+	m.Origin = append([]float64{}, p...)
+}
+
+// Nearest snaps p to the grid point closest to it and, if linear
+// constraints are set, to the nearest feasible point from there.
+func (m *Infinite) Nearest(p []float64) []float64 {
+	step := m.StepSize
+	if step == 0 {
+		step = 1
+	}
+
+	rel := p
+	if m.Origin != nil {
+		rel = make([]float64, len(p))
+		for i, v := range p {
+			rel[i] = v - m.Origin[i]
+		}
+	}
+
+	coefs := rel
+	if m.Basis != nil {
+		x := mat64.NewDense(len(rel), 1, rel)
+		c, err := mat64.Solve(m.Basis, x)
+		if err == nil {
+			coefs = c.Col(nil, 0)
+		}
+	}
+
+	snapped := make([]float64, len(coefs))
+	for i, c := range coefs {
+		snapped[i] = round(c/step) * step
+	}
+
+	if m.Basis != nil {
+		c := mat64.NewDense(len(snapped), 1, snapped)
+		x := &mat64.Dense{}
+		x.Mul(m.Basis, c)
+		snapped = x.Col(nil, 0)
+	}
+
+	if m.Origin != nil {
+		for i := range snapped {
+			snapped[i] += m.Origin[i]
+		}
+	}
+
+	if m.A == nil && m.E == nil {
+		return snapped
+	}
+
+	proj, err := Nearest(snapped, m.A, m.B, m.E, m.F)
+	if err != nil {
+		return snapped
+	}
+	return proj
+}
+
+func round(x float64) float64 {
+	return math.Floor(x + 0.5)
+}
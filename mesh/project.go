@@ -1,7 +1,7 @@
 package mesh
 
 import (
-	"fmt"
+	"errors"
 	"math"
 
 	"github.com/gonum/matrix/mat64"
@@ -66,86 +66,317 @@ func eye(n int) *mat64.Dense {
 	return m
 }
 
-// Nearest returns the nearest point to x0 that doesn't violate constraints in
-// the equation Ax <= b.
-func Nearest(x0 []float64, A, b *mat64.Dense) (proj []float64, success bool) {
-	from := x0
-	proj = x0
-	var badA *mat64.Dense
-	var badb *mat64.Dense
-	i := 0
-	failcount := 0
-	for {
-		i++
-		fmt.Println("iter ", i)
-		Aviol, bviol := mostviolated(proj, A, b)
-
-		if Aviol == nil { // projection is complete
-			fmt.Println("succeeded:", from, " -->", proj)
-			return proj, true
+const projEps = 1e-8
+
+// Nearest returns the point in the feasible region defined by the linear
+// inequality system Ax <= b (and, optionally, the linear equality system
+// Ex = f) that is closest in Euclidean distance to x0.  A nil A (or E)
+// means "no inequality (or equality) constraints".
+//
+// It uses a primal active-set quadratic-program solver: it starts from a
+// feasible seed (projecting onto the equality subspace via OrthoProj and
+// then walking to feasibility), then maintains a working set W of currently
+// active inequality rows.  At each step it solves the equality-constrained
+// subproblem on W (via OrthoProj, since the KKT system for this quadratic
+// objective has a closed form) to get a candidate point, takes a ratio-test
+// step toward it that stops at the nearest newly-blocking constraint (which
+// is then added to W), and, once the step is ~0, computes the Lagrange
+// multipliers λ for the rows in W (solved jointly with the E rows'
+// multipliers ν so any equality-subspace component can't leak into λ): if
+// all λ ≥ 0 the
+// point is optimal, otherwise the most-negative row is dropped from W and
+// the process continues.  An error is returned if no feasible point exists
+// or the active-set iteration fails to converge.
+func Nearest(x0 []float64, A, b, E, f *mat64.Dense) ([]float64, error) {
+	n := len(x0)
+
+	x, W, err := feasibleStart(x0, A, b, E, f)
+	if err != nil {
+		return nil, err
+	}
+
+	maxiter := 50 * (n + nrows(A) + nrows(E) + 1)
+	for iter := 0; iter < maxiter; iter++ {
+		Aw, bw := stack(E, f, rowsOf(A, W), colOf(b, W))
+
+		var xstar []float64
+		if Aw == nil {
+			xstar = x0
 		} else {
-			if badA == nil {
-				badA, badb = Aviol, bviol
-			} else {
-				tmpA, tmpb := badA, badb
-				badA, badb = &mat64.Dense{}, &mat64.Dense{}
-				badA.Stack(tmpA, Aviol)
-				badb.Stack(tmpb, bviol)
+			xstar, err = OrthoProj(x0, Aw, bw)
+			if err != nil {
+				return nil, err
 			}
 		}
 
-		fmt.Println("proj: ", proj)
-		fmt.Println("badA: ")
-		m, _ := badA.Dims()
-		for i := 0; i < m; i++ {
-			fmt.Println("  ", i, badA.Row(nil, i), "    :  b =", badb.At(i, 0))
+		p := sub(xstar, x)
+		if l2norm(p) < projEps {
+			// at the minimizer of the current working set -- check optimality.
+			if len(W) == 0 {
+				return x, nil
+			}
+
+			Wa := rowsOf(A, W)
+			lambda, err := multipliers(x0, x, E, Wa)
+			if err != nil {
+				return nil, err
+			}
+
+			worst, worstLambda := -1, -projEps
+			for i, l := range lambda {
+				if l < worstLambda {
+					worst, worstLambda = i, l
+				}
+			}
+			if worst == -1 {
+				return x, nil
+			}
+			W = append(W[:worst], W[worst+1:]...)
+			continue
+		}
+
+		alpha, block := ratioTest(x, p, A, b, W)
+		if alpha >= 1 {
+			x = xstar
+			continue
 		}
+		x = addscaled(x, p, alpha)
+		W = append(W, block)
+	}
+
+	return nil, errors.New("mesh: active-set projection failed to converge")
+}
 
-		newproj, err := OrthoProj(from, badA, badb)
+// feasibleStart finds a point satisfying Ex=f (if given) and Ax<=b, walking
+// from x0 by repeatedly projecting onto the most-violated constraints seen
+// so far.  It returns the feasible point along with the set of inequality
+// rows that ended up active (on the boundary) at that point, so the caller
+// can seed its working set from them.
+func feasibleStart(x0 []float64, A, b, E, f *mat64.Dense) ([]float64, []int, error) {
+	n := len(x0)
+
+	x := x0
+	if E != nil {
+		proj, err := OrthoProj(x0, E, f)
 		if err != nil {
-			failcount++
-			from = proj
-			badA, badb = nil, nil
-			if failcount == 2 {
-				fmt.Println("failed:", from, " -->", proj)
-				return proj, false
-			}
-		} else {
-			proj = newproj
+			return nil, nil, err
 		}
+		x = proj
+	}
+
+	if A == nil {
+		return x, nil, nil
 	}
+
+	var active []int
+	maxiter := 50 * (n + nrows(A) + 1)
+	for iter := 0; iter < maxiter; iter++ {
+		i, _ := mostViolated(x, A, b)
+		if i == -1 {
+			return x, active, nil
+		}
+
+		active = appendUnique(active, i)
+		Aw, bw := stack(E, f, rowsOf(A, active), colOf(b, active))
+		proj, err := OrthoProj(x0, Aw, bw)
+		if err != nil {
+			return nil, nil, err
+		}
+		x = proj
+	}
+
+	return nil, nil, errors.New("mesh: no feasible point found")
 }
 
-// mostviolated returns the most violated constraint in the system Ax <= b.
-// Aviol and b each have one row and len(x0) columns. It returns nil, nil if
-// x0 violates no constraints.  The most violated constraint is the one where
-// the (orthogonal) distance from x0 to the constraint/hyperplane is largest.
-func mostviolated(x0 []float64, A, b *mat64.Dense) (Aviol, bviol *mat64.Dense) {
-	eps := 1e-5
+// mostViolated returns the index of the most-violated row of Ax<=b at x
+// (the one farthest, in orthogonal distance, from its hyperplane), or -1 if
+// no row is violated beyond projEps.
+func mostViolated(x []float64, A, b *mat64.Dense) (idx int, amount float64) {
+	m, _ := A.Dims()
+	worst, worstDist := -1, 0.0
+	for i := 0; i < m; i++ {
+		row := A.Row(nil, i)
+		diff := dot(row, x) - b.At(i, 0)
+		if diff <= projEps {
+			continue
+		}
+		d := diff / l2norm(row)
+		if d > worstDist {
+			worst, worstDist = i, d
+		}
+	}
+	return worst, worstDist
+}
 
-	ax := &mat64.Dense{}
-	xm := mat64.NewDense(len(x0), 1, x0)
-	ax.Mul(A, xm)
-	m, _ := ax.Dims()
+// ratioTest returns the largest alpha in [0,1] such that x+alpha*p does not
+// violate any row of Ax<=b outside the working set W, along with the index
+// of the row that becomes active at that alpha (-1 if alpha==1, i.e. no new
+// row blocks the step).
+func ratioTest(x, p []float64, A, b *mat64.Dense, W []int) (alpha float64, block int) {
+	alpha, block = 1, -1
+	if A == nil {
+		return alpha, block
+	}
 
-	farthest := 0.0
-	worstRow := -1
+	m, _ := A.Dims()
 	for i := 0; i < m; i++ {
-		if diff := ax.At(i, 0) - b.At(i, 0); diff > eps {
-			// compute distance from x0 to plane of this violated constraint
-			d := (ax.At(i, 0) - b.At(i, 0)) / l2norm(A.Row(nil, i))
-			if d > farthest {
-				farthest = d
-				worstRow = i
-			}
+		if contains(W, i) {
+			continue
+		}
+		row := A.Row(nil, i)
+		ap := dot(row, p)
+		if ap <= projEps {
+			continue
+		}
+		a := (b.At(i, 0) - dot(row, x)) / ap
+		if a < alpha {
+			alpha, block = a, i
 		}
 	}
-	if worstRow == -1 {
+	if alpha < 0 {
+		alpha = 0
+	}
+	return alpha, block
+}
+
+// multipliers returns the Lagrange multipliers λ for the working-set rows
+// Aw of the equality-constrained subproblem that produced x.  At that
+// subproblem's minimizer, stationarity reads x0-x = Eᵀν + Awᵀλ, so when E
+// is non-nil the two multiplier sets must be solved for together -- via
+// the stacked system [E;Aw]*[E;Aw]ᵀ*[ν;λ] = [E;Aw]*(x0-x) -- and only the
+// λ (Aw) portion returned; solving on Aw alone would let the Eᵀν term leak
+// into λ and corrupt the optimality test.
+func multipliers(x0, x []float64, E, Aw *mat64.Dense) ([]float64, error) {
+	diff := mat64.NewDense(len(x0), 1, sub(x0, x))
+
+	stacked := stackRows(E, Aw)
+	stackedT := &mat64.Dense{}
+	stackedT.TCopy(stacked)
+
+	gram := &mat64.Dense{}
+	gram.Mul(stacked, stackedT)
+
+	inv, err := mat64.Inverse(gram)
+	if err != nil {
+		return nil, err
+	}
+
+	rhs := &mat64.Dense{}
+	rhs.Mul(stacked, diff)
+
+	mult := &mat64.Dense{}
+	mult.Mul(inv, rhs)
+	return mult.Col(nil, 0)[nrows(E):], nil
+}
+
+// stackRows vertically combines E and A, skipping either side if nil.
+func stackRows(E, A *mat64.Dense) *mat64.Dense {
+	switch {
+	case E == nil && A == nil:
+		return nil
+	case E == nil:
+		return A
+	case A == nil:
+		return E
+	default:
+		out := &mat64.Dense{}
+		out.Stack(E, A)
+		return out
+	}
+}
+
+func nrows(m *mat64.Dense) int {
+	if m == nil {
+		return 0
+	}
+	r, _ := m.Dims()
+	return r
+}
+
+// rowsOf returns the submatrix of A containing the given row indices, or nil
+// if A is nil or idxs is empty.
+func rowsOf(A *mat64.Dense, idxs []int) *mat64.Dense {
+	if A == nil || len(idxs) == 0 {
+		return nil
+	}
+	_, n := A.Dims()
+	rows := mat64.NewDense(len(idxs), n, nil)
+	for i, idx := range idxs {
+		rows.SetRow(i, A.Row(nil, idx))
+	}
+	return rows
+}
+
+// colOf returns the subvector (as an mx1 matrix) of b containing the given
+// row indices, or nil if b is nil or idxs is empty.
+func colOf(b *mat64.Dense, idxs []int) *mat64.Dense {
+	if b == nil || len(idxs) == 0 {
+		return nil
+	}
+	col := mat64.NewDense(len(idxs), 1, nil)
+	for i, idx := range idxs {
+		col.Set(i, 0, b.At(idx, 0))
+	}
+	return col
+}
+
+// stack vertically combines the (E,f) and (A,b) systems, skipping either
+// side if nil.  It returns nil, nil if both are nil.
+func stack(E, f, A, b *mat64.Dense) (*mat64.Dense, *mat64.Dense) {
+	switch {
+	case E == nil && A == nil:
 		return nil, nil
+	case E == nil:
+		return A, b
+	case A == nil:
+		return E, f
+	default:
+		stackedA := &mat64.Dense{}
+		stackedA.Stack(E, A)
+		stackedB := &mat64.Dense{}
+		stackedB.Stack(f, b)
+		return stackedA, stackedB
+	}
+}
+
+func dot(a, x []float64) float64 {
+	tot := 0.0
+	for i := range a {
+		tot += a[i] * x[i]
+	}
+	return tot
+}
+
+func sub(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] - b[i]
+	}
+	return out
+}
+
+func addscaled(x, p []float64, alpha float64) []float64 {
+	out := make([]float64, len(x))
+	for i := range x {
+		out[i] = x[i] + alpha*p[i]
+	}
+	return out
+}
+
+func contains(idxs []int, i int) bool {
+	for _, idx := range idxs {
+		if idx == i {
+			return true
+		}
 	}
-	fmt.Println("worstrow=", worstRow, ", farthest=", farthest)
+	return false
+}
 
-	return mat64.NewDense(1, len(x0), A.Row(nil, worstRow)), mat64.NewDense(1, 1, b.Row(nil, worstRow))
+func appendUnique(idxs []int, i int) []int {
+	if contains(idxs, i) {
+		return idxs
+	}
+	return append(idxs, i)
 }
 
 func l2norm(v []float64) float64 {
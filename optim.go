@@ -1,6 +1,7 @@
 package optim
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/binary"
 	"fmt"
@@ -54,8 +55,10 @@ func hashPoint(p Point) [sha1.Size]byte {
 
 type Iterator interface {
 	// Iterate runs a single iteration of a solver and reports the number of
-	// function evaluations n and the best point.
-	Iterate(obj Objectiver, m mesh.Mesh) (best Point, n int, err error)
+	// function evaluations n and the best point.  ctx may be used to
+	// cancel a long-running iteration early; implementations should return
+	// ctx.Err() if they stop early because of it.
+	Iterate(ctx context.Context, obj Objectiver, m mesh.Mesh) (best Point, n int, err error)
 
 	AddPoint(p Point)
 }
@@ -63,8 +66,10 @@ type Iterator interface {
 type Evaler interface {
 	// Eval evaluates each point using obj and returns the values and number
 	// of function evaluations n.  Unevaluated points should not be returned
-	// in the results slice.
-	Eval(obj Objectiver, points ...Point) (results []Point, n int, err error)
+	// in the results slice.  ctx may be used to cancel evaluation of the
+	// remaining points early; implementations should return ctx.Err() if
+	// they stop early because of it.
+	Eval(ctx context.Context, obj Objectiver, points ...Point) (results []Point, n int, err error)
 }
 
 type Objectiver interface {
@@ -88,19 +93,19 @@ func NewCacheEvaler(ev Evaler) *CacheEvaler {
 	}
 }
 
-func (ev *CacheEvaler) Eval(obj Objectiver, points ...Point) (results []Point, n int, err error) {
+func (ev *CacheEvaler) Eval(ctx context.Context, obj Objectiver, points ...Point) (results []Point, n int, err error) {
 	fromnew := make([]int, 0, len(points))
 	newp := make([]Point, 0, len(points))
 	for i, p := range points {
 		if val, ok := ev.cache[hashPoint(p)]; ok {
-			p.Val = val
+			points[i].Val = val
 		} else {
 			fromnew = append(fromnew, i)
 			newp = append(newp, p)
 		}
 	}
 
-	newresults, n, err := ev.ev.Eval(obj, newp...)
+	newresults, n, err := ev.ev.Eval(ctx, obj, newp...)
 	for _, p := range newresults {
 		ev.cache[hashPoint(p)] = p.Val
 	}
@@ -121,9 +126,12 @@ type SerialEvaler struct {
 	ContinueOnErr bool
 }
 
-func (ev SerialEvaler) Eval(obj Objectiver, points ...Point) (results []Point, n int, err error) {
+func (ev SerialEvaler) Eval(ctx context.Context, obj Objectiver, points ...Point) (results []Point, n int, err error) {
 	results = make([]Point, 0, len(points))
 	for _, p := range points {
+		if err = ctx.Err(); err != nil {
+			return results, len(results), err
+		}
 		p.Val, err = obj.Objective(p.Pos())
 		results = append(results, p)
 		if err != nil && !ev.ContinueOnErr {
@@ -133,35 +141,101 @@ func (ev SerialEvaler) Eval(obj Objectiver, points ...Point) (results []Point, n
 	return results, len(results), nil
 }
 
-type errpoint struct {
-	Point
-	Err error
+// ParallelEvaler evaluates points concurrently across a fixed pool of
+// Workers goroutines (a single worker if Workers <= 0).  Eval stops
+// dispatching new points as soon as either the passed-in ctx or (if set)
+// Ctx is done, returning that context's Err() alongside whatever results
+// were already computed -- Ctx supplements the caller's ctx rather than
+// replacing it, so cancellation from either source is honored.  If any
+// Objective call returns an error, Eval returns one of them (whichever is
+// observed first in goroutine completion order, which is not necessarily
+// the first one dispatched) alongside all results gathered before and
+// after it.
+type ParallelEvaler struct {
+	Workers int
+	Ctx     context.Context
 }
 
-type ParallelEvaler struct{}
+func (ev ParallelEvaler) Eval(ctx context.Context, obj Objectiver, points ...Point) (results []Point, n int, err error) {
+	workers := ev.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// unbuffered, so a point is only handed over once a worker is free and
+	// cancellation can stop the feed loop below partway through.
+	in := make(chan int)
+	out := make(chan indexedPoint, len(points))
 
-func (ev ParallelEvaler) Eval(obj Objectiver, points ...Point) (results []Point, n int, err error) {
-	results = make([]Point, 0, len(points))
 	wg := sync.WaitGroup{}
-	wg.Add(len(points))
-	ch := make(chan errpoint, len(points))
-	for _, p := range points {
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
 		go func() {
-			perr := errpoint{Point: p}
-			perr.Val, perr.Err = obj.Objective(p.Pos())
-			ch <- perr
-			wg.Done()
+			defer wg.Done()
+			for i := range in {
+				p := points[i]
+				var everr error
+				p.Val, everr = obj.Objective(p.Pos())
+				out <- indexedPoint{i: i, p: p, err: everr}
+			}
 		}()
 	}
 
-	for p := range ch {
-		if p.Err != nil {
-			err = p.Err
+feed:
+	for i := range points {
+		select {
+		case in <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		case <-doneCh(ev.Ctx):
+			err = ev.Ctx.Err()
+			break feed
+		}
+	}
+	close(in)
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	got := make([]Point, len(points))
+	filled := make([]bool, len(points))
+	for ip := range out {
+		got[ip.i] = ip.p
+		filled[ip.i] = true
+		n++
+		if ip.err != nil && err == nil {
+			err = ip.err
+		}
+	}
+
+	// preserve the caller's input ordering in the returned slice, skipping
+	// any points that were never dispatched because ctx was canceled
+	// (points already handed to a worker are evaluated to completion).
+	results = make([]Point, 0, n)
+	for i, ok := range filled {
+		if ok {
+			results = append(results, got[i])
 		}
-		results = append(results, p.Point)
 	}
-	wg.Wait()
-	return results, len(results), err
+	return results, n, err
+}
+
+// doneCh returns ctx.Done(), or a nil channel (which blocks forever in a
+// select, the desired no-op) if ctx is nil.
+func doneCh(ctx context.Context) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Done()
+}
+
+type indexedPoint struct {
+	i   int
+	p   Point
+	err error
 }
 
 type Func func([]float64) float64
@@ -253,3 +327,221 @@ func (o *ObjectivePenalty) Objective(v []float64) (float64, error) {
 
 	return val * (1 + penalty), err
 }
+
+// Solver drives an Iterator against Obj -- passing Mesh (if set) to each
+// iteration so the Iterator can restrict its points to it -- until either
+// MaxIter iterations or MaxEval evaluations have been spent.
+type Solver struct {
+	Method  Iterator
+	Obj     Objectiver
+	Mesh    mesh.Mesh
+	MaxIter int
+	MaxEval int
+	// Resume, if set, makes Run checkpoint its progress to (and, on a
+	// resumed run, seed its search from) a database -- see Resume.
+	Resume *Resume
+}
+
+// Run iterates s.Method until the iteration/evaluation budget is spent, ctx
+// is canceled, or an iteration returns an error, and returns the best point
+// found along with the number of iterations and evaluations spent.
+func (s *Solver) Run(ctx context.Context) (best Point, niter, neval int, err error) {
+	best = Point{Val: math.Inf(1)}
+
+	if s.Resume != nil {
+		if err = s.Resume.seed(s); err != nil {
+			return best, 0, 0, err
+		}
+	}
+
+	for niter = 0; (s.MaxIter <= 0 || niter < s.MaxIter) && (s.MaxEval <= 0 || neval < s.MaxEval); niter++ {
+		p, n, ierr := s.Method.Iterate(ctx, s.Obj, s.Mesh)
+		neval += n
+		if p.Val < best.Val {
+			best = p
+		}
+
+		if s.Resume != nil {
+			if rerr := s.Resume.record(niter, best); rerr != nil && ierr == nil {
+				ierr = rerr
+			}
+		}
+
+		if ierr != nil {
+			return best, niter + 1, neval, ierr
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return best, niter + 1, neval, cerr
+		}
+	}
+	return best, niter, neval, nil
+}
+
+// AugmentedLagrangian wraps an Objectiver and adds an augmented-Lagrangian
+// penalty for the linear constraints Low <= A*x <= Up, evaluating
+//
+//	L(x; λ, μ) = f(x) + Σ [ λ_i c_i(x) + (μ/2) c_i(x)² ]
+//
+// summed only over the violated side of each row, where c_i(x) is the
+// signed violation of row i (positive above Up, negative below Low, zero
+// if satisfied). Call Update between outer iterations to adapt λ and μ
+// toward an asymptotically exact constrained optimum (the Hestenes-Powell
+// rule) instead of hand-tuning a static penalty Weight as ObjectivePenalty
+// requires.
+type AugmentedLagrangian struct {
+	A       *mat64.Dense
+	Low, Up *mat64.Dense
+	Obj     Objectiver
+	// Mu is the current penalty weight. If zero, it defaults to 1 on the
+	// first call to Objective or Update. Otherwise it should be left at
+	// its initial value (or set to a custom positive seed) and then only
+	// adapted via Update.
+	Mu float64
+	// MuCap stops Update from growing Mu past this value. Zero means no
+	// cap.
+	MuCap float64
+
+	lambda  []float64
+	maxViol float64
+	started bool
+}
+
+func (al *AugmentedLagrangian) Objective(v []float64) (float64, error) {
+	val, err := al.Obj.Objective(v)
+
+	m, _ := al.A.Dims()
+	if al.lambda == nil {
+		al.lambda = make([]float64, m)
+	}
+	if al.Mu == 0 {
+		al.Mu = 1
+	}
+
+	ax := al.evalRows(v)
+	for i := 0; i < m; i++ {
+		c := al.violation(i, ax[i])
+		val += al.lambda[i]*c + al.Mu/2*c*c
+	}
+	return val, err
+}
+
+// Update adapts λ and μ from the violations at bestX: μ grows by a factor
+// of 10 (clamped to MuCap, if set) if the maximum violation didn't shrink
+// by at least a quarter since the last call, and each λ_i moves toward
+// λ_i + μ*c_i(bestX) for rows currently in violation.
+func (al *AugmentedLagrangian) Update(bestX []float64) {
+	m, _ := al.A.Dims()
+	if al.lambda == nil {
+		al.lambda = make([]float64, m)
+	}
+	if al.Mu == 0 {
+		al.Mu = 1
+	}
+
+	ax := al.evalRows(bestX)
+	cs := make([]float64, m)
+	maxViol := 0.0
+	for i := 0; i < m; i++ {
+		cs[i] = al.violation(i, ax[i])
+		if v := math.Abs(cs[i]); v > maxViol {
+			maxViol = v
+		}
+	}
+
+	if al.started && maxViol > 0.75*al.maxViol {
+		if al.MuCap <= 0 {
+			al.Mu *= 10
+		} else if al.Mu < al.MuCap {
+			al.Mu = math.Min(al.Mu*10, al.MuCap)
+		}
+	}
+	al.maxViol = maxViol
+	al.started = true
+
+	for i, c := range cs {
+		if c != 0 {
+			al.lambda[i] += al.Mu * c
+		}
+	}
+}
+
+// MaxViolation returns the largest constraint violation magnitude observed
+// at the last call to Update.
+func (al *AugmentedLagrangian) MaxViolation() float64 { return al.maxViol }
+
+func (al *AugmentedLagrangian) evalRows(v []float64) []float64 {
+	x := mat64.NewDense(len(v), 1, v)
+	ax := &mat64.Dense{}
+	ax.Mul(al.A, x)
+	m, _ := ax.Dims()
+	out := make([]float64, m)
+	for i := 0; i < m; i++ {
+		out[i] = ax.At(i, 0)
+	}
+	return out
+}
+
+func (al *AugmentedLagrangian) violation(i int, axi float64) float64 {
+	if d := axi - al.Up.At(i, 0); d > 0 {
+		return d
+	}
+	if d := axi - al.Low.At(i, 0); d < 0 {
+		return d
+	}
+	return 0
+}
+
+// Resetter is implemented by an Iterator that holds adapted internal state
+// (a step size, an evolution path, etc.) which needs to be reinitialized
+// before the Iterator is reused from scratch against a new objective --
+// e.g. by AugLagSolver, whose Inner.Method solves a new penalized
+// objective each outer iteration and would otherwise inherit convergence
+// state (such as a shrunk poll step) left over from the previous one.
+type Resetter interface {
+	Reset()
+}
+
+// AugLagSolver minimizes a linearly-constrained objective by repeatedly
+// running Inner's unconstrained solve against AL, calling AL.Update between
+// outer iterations, until the max violation drops below Tol or AL.Mu hits
+// AL.MuCap. If Inner.Method implements Resetter, it is Reset before each
+// outer iteration's inner solve so adapted state from the previous
+// iteration's (differently penalized) objective doesn't carry over.
+type AugLagSolver struct {
+	AL       *AugmentedLagrangian
+	Inner    *Solver // Inner.Obj is overwritten with AL on each call to Run
+	Tol      float64
+	MaxOuter int
+}
+
+// Run drives the outer augmented-Lagrangian loop and returns the best
+// feasible-ish point found, along with the number of outer iterations and
+// total evaluations spent across all of them.
+func (s *AugLagSolver) Run(ctx context.Context) (best Point, nouter, neval int, err error) {
+	s.Inner.Obj = s.AL
+
+	for nouter = 0; s.MaxOuter <= 0 || nouter < s.MaxOuter; nouter++ {
+		if r, ok := s.Inner.Method.(Resetter); ok {
+			r.Reset()
+		}
+
+		p, _, n, ierr := s.Inner.Run(ctx)
+		neval += n
+		best = p
+		if ierr != nil {
+			return best, nouter + 1, neval, ierr
+		}
+
+		s.AL.Update(best.Pos())
+		if s.AL.MaxViolation() < s.Tol {
+			return best, nouter + 1, neval, nil
+		}
+		if s.AL.MuCap > 0 && s.AL.Mu >= s.AL.MuCap {
+			return best, nouter + 1, neval, nil
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return best, nouter + 1, neval, cerr
+		}
+	}
+	return best, nouter, neval, nil
+}
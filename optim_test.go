@@ -0,0 +1,62 @@
+package optim_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/rwcarlsen/optim"
+	"github.com/rwcarlsen/optim/mesh"
+	"github.com/rwcarlsen/optim/pattern"
+)
+
+// TestAugLagSolverConverges is a regression test for a bug where
+// AugLagSolver reused its Inner Method across outer iterations without
+// resetting its adapted state: pattern.Poller's step would shrink below
+// SkipEps during the first inner solve and never recover, so later inner
+// solves were no-ops and the outer loop just ratcheted Mu to MuCap
+// without ever reducing the constraint violation.
+func TestAugLagSolverConverges(t *testing.T) {
+	// minimize (x-2)^2+(y-2)^2 s.t. x+y <= 1; the unconstrained optimum
+	// (2,2) violates the constraint, forcing the outer loop to adapt.
+	obj := optim.Func(func(v []float64) float64 {
+		dx, dy := v[0]-2, v[1]-2
+		return dx*dx + dy*dy
+	})
+
+	A := mat64.NewDense(1, 2, []float64{1, 1})
+	up := mat64.NewDense(1, 1, []float64{1})
+	low := mat64.NewDense(1, 1, []float64{math.Inf(-1)})
+
+	al := &optim.AugmentedLagrangian{A: A, Low: low, Up: up, Obj: obj}
+
+	m := &mesh.Infinite{StepSize: 0.01}
+	start := optim.NewPoint([]float64{2, 2}, math.Inf(1))
+	m.SetOrigin(start.Pos())
+
+	solver := &optim.AugLagSolver{
+		AL: al,
+		Inner: &optim.Solver{
+			Method:  pattern.New(start),
+			Mesh:    m,
+			MaxIter: 300,
+		},
+		Tol:      0.05,
+		MaxOuter: 30,
+	}
+
+	best, nouter, _, err := solver.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if nouter <= 1 {
+		t.Fatalf("expected convergence to take more than one outer iteration, got %d", nouter)
+	}
+	if v := al.MaxViolation(); v >= solver.Tol {
+		t.Fatalf("MaxViolation = %v, want < Tol = %v", v, solver.Tol)
+	}
+	if c := best.Pos()[0] + best.Pos()[1]; c > 1+solver.Tol {
+		t.Fatalf("constraint x+y<=1 violated at best point: got %v", c)
+	}
+}
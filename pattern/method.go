@@ -0,0 +1,160 @@
+// Package pattern implements generalized pattern search: each iteration
+// optionally consults a Searcher for an opportunistic improving point and
+// then polls the mesh around the current best point.
+package pattern
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/rwcarlsen/optim"
+	"github.com/rwcarlsen/optim/mesh"
+)
+
+// ShareMode controls whether points evaluated by a Method's search step are
+// also fed back into its poll step (and vice versa).
+type ShareMode int
+
+const (
+	// Share lets the poll and search steps see each other's evaluated
+	// points, so a promising point found by one seeds the other.
+	Share ShareMode = iota
+	// NoShare does not forward the poll step's points to the search step.
+	// The two are not fully independent: in either mode the search step's
+	// candidates update the Method's best point, and each search starts
+	// from that best point.
+	NoShare
+)
+
+// Searcher generates a candidate improving point around best.  It is the
+// "search" half of generalized pattern search, tried each iteration before
+// the poll step (which runs whether or not the search improved).
+type Searcher interface {
+	Search(ctx context.Context, obj optim.Objectiver, m mesh.Mesh, best optim.Point) (cands []optim.Point, n int, err error)
+}
+
+// Method implements generalized pattern search as an optim.Iterator.
+type Method struct {
+	Poller *Poller
+
+	searcher Searcher
+	mode     ShareMode
+	db       *sql.DB
+
+	best optim.Point
+}
+
+// Option configures a Method created by New.
+type Option func(*Method)
+
+// DB sets the *sql.DB a Method uses to persist evaluated points, enabling
+// resumable runs.  A nil db disables persistence.
+func DB(db *sql.DB) Option {
+	return func(m *Method) { m.db = db }
+}
+
+// SearchMethod configures m to consult it as the search step before
+// polling, sharing evaluated points between the two according to mode.  it
+// may be a Searcher directly (e.g. ModelSearch) or any optim.Iterator (e.g.
+// a swarm), which is adapted into a Searcher by running one of its
+// iterations per call.
+func SearchMethod(it interface{}, mode ShareMode) Option {
+	var s Searcher
+	switch v := it.(type) {
+	case Searcher:
+		s = v
+	case optim.Iterator:
+		s = &iterSearcher{it: v}
+	}
+	return func(m *Method) {
+		m.searcher = s
+		m.mode = mode
+	}
+}
+
+// iterSearcher adapts an arbitrary optim.Iterator (e.g. a swarm) into a
+// Searcher by running one of its iterations per Search call.
+type iterSearcher struct {
+	it optim.Iterator
+}
+
+func (s *iterSearcher) Search(ctx context.Context, obj optim.Objectiver, m mesh.Mesh, best optim.Point) ([]optim.Point, int, error) {
+	s.it.AddPoint(best)
+	p, n, err := s.it.Iterate(ctx, obj, m)
+	if err != nil {
+		return nil, n, err
+	}
+	return []optim.Point{p}, n, nil
+}
+
+// AddPoint forwards p to the adapted Iterator.
+func (s *iterSearcher) AddPoint(p optim.Point) { s.it.AddPoint(p) }
+
+// New creates a pattern search Method seeded at initial.
+func New(initial optim.Point, opts ...Option) *Method {
+	m := &Method{
+		Poller: &Poller{SpanFn: CompassNp1},
+		best:   initial,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// AddPoint updates m's notion of the best point found so far.
+func (m *Method) AddPoint(p optim.Point) {
+	if p.Val < m.best.Val {
+		m.best = p
+	}
+	if m.searcher != nil && m.mode == Share {
+		if adder, ok := m.searcher.(interface{ AddPoint(optim.Point) }); ok {
+			adder.AddPoint(p)
+		}
+	}
+}
+
+// Iterate runs one pattern-search iteration: a search step (if configured)
+// followed by a poll step, and returns the best point found across both.
+// ctx may be used to cancel a long-running iteration early.
+func (m *Method) Iterate(ctx context.Context, obj optim.Objectiver, msh mesh.Mesh) (best optim.Point, n int, err error) {
+	if err = ctx.Err(); err != nil {
+		return m.best, 0, err
+	}
+
+	if m.searcher != nil {
+		cands, sn, serr := m.searcher.Search(ctx, obj, msh, m.best)
+		n += sn
+		if serr == nil {
+			for _, c := range cands {
+				m.AddPoint(c)
+			}
+		}
+	}
+
+	polled, pn, perr := m.Poller.Poll(ctx, obj, msh, m.best)
+	n += pn
+	if perr != nil {
+		return m.best, n, perr
+	}
+	m.AddPoint(polled)
+	return m.best, n, nil
+}
+
+// Reset clears m's adapted search state -- the poll step size, and, if the
+// configured search step supports it, its own state -- so the next
+// Iterate starts fresh rather than continuing from wherever a previous
+// series of calls left it. Useful when m is being reused against a new
+// objective, e.g. by AugLagSolver between outer iterations. The best
+// point's position is kept but its value is forgotten, since it was
+// computed under the previous objective.
+func (m *Method) Reset() {
+	m.best = optim.NewPoint(m.best.Pos(), math.Inf(1))
+	if m.Poller != nil {
+		m.Poller.Reset()
+	}
+	if r, ok := m.searcher.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+}
@@ -0,0 +1,347 @@
+package pattern
+
+import (
+	"context"
+	"math"
+
+	"github.com/rwcarlsen/optim"
+	"github.com/rwcarlsen/optim/mesh"
+)
+
+// ModelSearch is a Searcher that, in the spirit of Powell's derivative-free
+// trust-region methods (e.g. BOBYQA), fits a local quadratic model from a
+// stencil of cached evaluations around the current best point and
+// minimizes it inside a trust region to propose a search-step candidate.
+//
+// The stencil's 2n+1 points determine the model's constant, gradient, and
+// diagonal Hessian entries exactly via central differences, but leave the
+// off-diagonal (cross-term) curvature undetermined. Rather than dropping
+// those terms, ModelSearch starts them at zero and folds each subsequent
+// trial point into the Hessian with the symmetric, minimum-Frobenius-norm
+// update of Powell (2004) -- the smallest possible change to the model's
+// curvature that also interpolates the new point. This lets the model
+// pick up variable coupling (e.g. Rosenbrock's valley) over the course of
+// a few iterations without paying for extra stencil evaluations. The
+// resulting trust-region subproblem is minimized with a box-constrained
+// truncated-CG (Steihaug) step. The stencil is reused, and the learned
+// cross terms kept, across iterations as long as the incumbent best point
+// hasn't moved; both are rebuilt from scratch (at the then-current
+// trust-region radius) once it has.
+type ModelSearch struct {
+	// Delta0 is the initial trust-region radius and stencil half-spacing.
+	// If zero, 1 is used.
+	Delta0 float64
+	// DeltaMin stops shrinking the trust region below this radius; once hit,
+	// Search reports no further candidates.
+	DeltaMin float64
+
+	delta        float64
+	stencilDelta float64
+	basis        []float64
+	y            []optim.Point // y[0] is the basis; y[1+2i], y[2+2i] are +/-delta along axis i
+	c            float64
+	g            []float64
+	h            [][]float64 // symmetric n x n Hessian
+}
+
+// Search proposes a single trust-region candidate around best.  ctx may be
+// used to cancel the underlying evaluations early.
+func (ms *ModelSearch) Search(ctx context.Context, obj optim.Objectiver, m mesh.Mesh, best optim.Point) (cands []optim.Point, n int, err error) {
+	if ms.delta == 0 {
+		ms.delta = ms.Delta0
+	}
+	if ms.delta == 0 {
+		ms.delta = 1
+	}
+	if ms.DeltaMin > 0 && ms.delta <= ms.DeltaMin {
+		return nil, 0, nil
+	}
+
+	if ms.y != nil {
+		// the incumbent moved (e.g. via the poll step) since the stencil
+		// was built, so it no longer describes best's neighborhood.
+		for i, x := range best.Pos() {
+			if x != ms.basis[i] {
+				ms.y = nil
+				break
+			}
+		}
+	}
+
+	if ms.y == nil {
+		stencil, sn, err := ms.buildStencil(ctx, obj, m, best)
+		n += sn
+		if err != nil {
+			return nil, n, err
+		}
+		ms.y = stencil
+		ms.fit()
+	}
+
+	step := ms.minimize()
+
+	pos := m.Nearest(addv(best.Pos(), step))
+	trial := optim.NewPoint(pos, math.Inf(1))
+
+	ev := optim.SerialEvaler{}
+	results, en, err := ev.Eval(ctx, obj, trial)
+	n += en
+	if err != nil || len(results) == 0 {
+		return nil, n, err
+	}
+	trial = results[0]
+
+	predicted := ms.predict(best.Pos()) - ms.predict(trial.Pos())
+	actual := best.Val - trial.Val
+
+	ratio := 0.0
+	if predicted > 0 {
+		ratio = actual / predicted
+	}
+
+	switch {
+	case ratio > 0.75:
+		ms.delta *= 2
+	case ratio < 0.25:
+		ms.delta = math.Max(ms.delta/2, ms.DeltaMin)
+	}
+
+	ms.updateHessian(trial)
+
+	if trial.Val < best.Val {
+		// the incumbent moved -- the stencil no longer describes the
+		// neighborhood of the (new) best point, so force a rebuild.
+		ms.y = nil
+	}
+
+	return []optim.Point{trial}, n, nil
+}
+
+// buildStencil evaluates the 2n points at +/-delta along each axis around
+// best (best itself needs no new evaluation).
+func (ms *ModelSearch) buildStencil(ctx context.Context, obj optim.Objectiver, m mesh.Mesh, best optim.Point) ([]optim.Point, int, error) {
+	n := best.Len()
+	pos := best.Pos()
+
+	offsets := make([]optim.Point, 0, 2*n)
+	for i := 0; i < n; i++ {
+		for _, sign := range [...]float64{1, -1} {
+			p := make([]float64, n)
+			copy(p, pos)
+			p[i] += sign * ms.delta
+			offsets = append(offsets, optim.NewPoint(m.Nearest(p), math.Inf(1)))
+		}
+	}
+
+	ev := optim.SerialEvaler{}
+	results, n2, err := ev.Eval(ctx, obj, offsets...)
+	if err != nil {
+		return nil, n2, err
+	}
+
+	ms.basis = pos
+	ms.stencilDelta = ms.delta
+	return append([]optim.Point{best}, results...), n2, nil
+}
+
+// fit derives the quadratic model's constant, gradient, and diagonal
+// Hessian entries from the stencil via central differences, zeroing the
+// cross terms (which the stencil alone cannot determine -- they are
+// learned afterward by updateHessian).
+func (ms *ModelSearch) fit() {
+	n := (len(ms.y) - 1) / 2
+	ms.c = ms.y[0].Val
+	ms.g = make([]float64, n)
+	ms.h = make([][]float64, n)
+	for i := range ms.h {
+		ms.h[i] = make([]float64, n)
+	}
+
+	d := ms.stencilDelta
+	for i := 0; i < n; i++ {
+		plus, minus := ms.y[1+2*i], ms.y[2+2*i]
+		ms.g[i] = (plus.Val - minus.Val) / (2 * d)
+		ms.h[i][i] = (plus.Val - 2*ms.c + minus.Val) / (d * d)
+	}
+}
+
+// updateHessian folds the newly evaluated trial point into the model's
+// Hessian via the symmetric, minimum-Frobenius-norm update: the smallest
+// (in Frobenius norm) symmetric change to H such that the model also
+// interpolates trial exactly. For a single new point this reduces to a
+// multiple of the outer product s*s^T, s = trial.Pos() - basis (Powell,
+// "Least Frobenius norm updating of quadratic models that satisfy
+// interpolation conditions", 2004).
+func (ms *ModelSearch) updateHessian(trial optim.Point) {
+	s := subv(trial.Pos(), ms.basis)
+	ss := dot(s, s)
+	if ss == 0 {
+		return
+	}
+
+	resid := trial.Val - ms.predict(trial.Pos())
+	coef := 2 * resid / (ss * ss)
+	for i := range s {
+		for j := range s {
+			ms.h[i][j] += coef * s[i] * s[j]
+		}
+	}
+}
+
+// minimize approximately minimizes the quadratic model within the box
+// trust region ||s||_inf <= delta via box-constrained truncated-CG
+// (Steihaug): standard CG on the model's Newton system, stopped and
+// projected to the trust-region boundary on negative curvature or once a
+// step would leave the box.
+func (ms *ModelSearch) minimize() []float64 {
+	n := len(ms.g)
+	s := make([]float64, n)
+
+	r := make([]float64, n)
+	for i := range r {
+		r[i] = -ms.g[i]
+	}
+	if dot(r, r) == 0 {
+		return s
+	}
+
+	d := append([]float64{}, r...)
+	rs := dot(r, r)
+
+	for k := 0; k < n; k++ {
+		hd := ms.matvec(d)
+		dhd := dot(d, hd)
+
+		if dhd <= 0 {
+			tau := boundaryStep(s, d, ms.delta)
+			return clipBox(addScaled(s, tau, d), ms.delta)
+		}
+
+		alpha := rs / dhd
+		next := addScaled(s, alpha, d)
+		if maxAbs(next) > ms.delta {
+			tau := boundaryStep(s, d, ms.delta)
+			return clipBox(addScaled(s, tau, d), ms.delta)
+		}
+		s = next
+
+		for i := range r {
+			r[i] -= alpha * hd[i]
+		}
+		rsNext := dot(r, r)
+		if math.Sqrt(rsNext) < 1e-12 {
+			break
+		}
+		beta := rsNext / rs
+		for i := range d {
+			d[i] = r[i] + beta*d[i]
+		}
+		rs = rsNext
+	}
+	return clipBox(s, ms.delta)
+}
+
+// matvec returns H*v for the model's (symmetric) Hessian.
+func (ms *ModelSearch) matvec(v []float64) []float64 {
+	out := make([]float64, len(v))
+	for i, row := range ms.h {
+		sum := 0.0
+		for j, hij := range row {
+			sum += hij * v[j]
+		}
+		out[i] = sum
+	}
+	return out
+}
+
+// predict evaluates the quadratic model at x.
+func (ms *ModelSearch) predict(x []float64) float64 {
+	dx := subv(x, ms.basis)
+	val := ms.c
+	for i, gi := range ms.g {
+		val += gi * dx[i]
+	}
+	val += 0.5 * dot(dx, ms.matvec(dx))
+	return val
+}
+
+// boundaryStep returns the smallest tau >= 0 such that s+tau*d first
+// reaches a face of the box ||.||_inf <= delta.
+func boundaryStep(s, d []float64, delta float64) float64 {
+	tau := math.Inf(1)
+	for i, di := range d {
+		if di == 0 {
+			continue
+		}
+		var t float64
+		if di > 0 {
+			t = (delta - s[i]) / di
+		} else {
+			t = (-delta - s[i]) / di
+		}
+		if t >= 0 && t < tau {
+			tau = t
+		}
+	}
+	if math.IsInf(tau, 1) {
+		return 0
+	}
+	return tau
+}
+
+func clipBox(s []float64, delta float64) []float64 {
+	out := make([]float64, len(s))
+	for i, v := range s {
+		switch {
+		case v > delta:
+			out[i] = delta
+		case v < -delta:
+			out[i] = -delta
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
+
+func maxAbs(v []float64) float64 {
+	m := 0.0
+	for _, x := range v {
+		if a := math.Abs(x); a > m {
+			m = a
+		}
+	}
+	return m
+}
+
+func dot(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func addScaled(a []float64, alpha float64, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] + alpha*b[i]
+	}
+	return out
+}
+
+func subv(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] - b[i]
+	}
+	return out
+}
+
+func addv(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] + b[i]
+	}
+	return out
+}
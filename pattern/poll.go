@@ -0,0 +1,99 @@
+package pattern
+
+import (
+	"context"
+	"math"
+
+	"github.com/rwcarlsen/optim"
+	"github.com/rwcarlsen/optim/mesh"
+)
+
+// SpanFunc generates a set of poll directions, as points offset from basis,
+// scaled by step.
+type SpanFunc func(basis optim.Point, step float64) []optim.Point
+
+// CompassNp1 generates the n+1 positive-spanning compass directions (the n
+// standard basis vectors plus their negated sum) scaled by step.
+func CompassNp1(basis optim.Point, step float64) []optim.Point {
+	n := basis.Len()
+	pos := basis.Pos()
+
+	sum := make([]float64, n)
+	pts := make([]optim.Point, 0, n+1)
+	for i := 0; i < n; i++ {
+		p := make([]float64, n)
+		copy(p, pos)
+		p[i] += step
+		sum[i] -= step
+		pts = append(pts, optim.NewPoint(p, math.Inf(1)))
+	}
+
+	last := make([]float64, n)
+	for i := range last {
+		last[i] = pos[i] + sum[i]
+	}
+	pts = append(pts, optim.NewPoint(last, math.Inf(1)))
+	return pts
+}
+
+// Poller implements the poll step of generalized pattern search: it
+// evaluates a positive-spanning set of directions (from SpanFn), snapped to
+// the mesh, around the basis point and returns the best one found, growing
+// the poll step on success and shrinking it on failure.
+type Poller struct {
+	// SkipEps skips the poll step (reporting no progress) once the poll
+	// step size has shrunk below this threshold, signaling convergence.
+	SkipEps float64
+	SpanFn  SpanFunc
+
+	step float64
+}
+
+// Poll evaluates one set of poll directions around basis and returns the
+// best point found (basis itself if nothing improved).  ctx may be used to
+// cancel the poll early.
+func (p *Poller) Poll(ctx context.Context, obj optim.Objectiver, m mesh.Mesh, basis optim.Point) (optim.Point, int, error) {
+	if p.step == 0 {
+		p.step = 1
+	}
+	if p.step < p.SkipEps {
+		return basis, 0, nil
+	}
+
+	spanfn := p.SpanFn
+	if spanfn == nil {
+		spanfn = CompassNp1
+	}
+
+	cands := spanfn(basis, p.step)
+	for i, c := range cands {
+		cands[i] = optim.NewPoint(m.Nearest(c.Pos()), c.Val)
+	}
+
+	ev := optim.SerialEvaler{ContinueOnErr: true}
+	results, n, err := ev.Eval(ctx, obj, cands...)
+	if err != nil {
+		return basis, n, err
+	}
+
+	best := basis
+	improved := false
+	for _, r := range results {
+		if r.Val < best.Val {
+			best, improved = r, true
+		}
+	}
+
+	if improved {
+		p.step *= 2
+	} else {
+		p.step /= 2
+	}
+	return best, n, nil
+}
+
+// Reset clears p's adapted step size, so the next Poll starts fresh
+// (step 1) rather than continuing from wherever a previous series of
+// calls left it. Useful when p is being reused against a new objective,
+// e.g. by AugLagSolver between outer iterations.
+func (p *Poller) Reset() { p.step = 0 }
@@ -0,0 +1,203 @@
+package optim
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// SQLCacheEvaler is like CacheEvaler but persists evaluated points to a
+// database instead of an in-memory map, so a later run against the same DB
+// can reuse the results of an earlier (possibly interrupted) one.
+type SQLCacheEvaler struct {
+	ev Evaler
+	db *sql.DB
+}
+
+// NewSQLCacheEvaler wraps ev with a cache backed by db, creating db's
+// eval_cache table if it doesn't already exist.
+func NewSQLCacheEvaler(ev Evaler, db *sql.DB) (*SQLCacheEvaler, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS eval_cache (
+			hash      BLOB PRIMARY KEY,
+			dim       INTEGER NOT NULL,
+			pos       BLOB NOT NULL,
+			val       REAL NOT NULL,
+			wall_time REAL NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLCacheEvaler{ev: ev, db: db}, nil
+}
+
+// Eval looks up each point in the eval_cache table by sha1(pos), evaluating
+// (and then persisting, in a single transaction) only those not already
+// cached.
+func (ev *SQLCacheEvaler) Eval(ctx context.Context, obj Objectiver, points ...Point) (results []Point, n int, err error) {
+	fromnew := make([]int, 0, len(points))
+	newp := make([]Point, 0, len(points))
+	for i, p := range points {
+		val, ok, lerr := ev.lookup(p)
+		if lerr != nil {
+			return nil, 0, lerr
+		}
+		if ok {
+			points[i].Val = val
+		} else {
+			fromnew = append(fromnew, i)
+			newp = append(newp, p)
+		}
+	}
+
+	newresults, n, err := ev.ev.Eval(ctx, obj, newp...)
+	if serr := ev.store(newresults); serr != nil && err == nil {
+		err = serr
+	}
+
+	for i, p := range newresults {
+		points[fromnew[i]].Val = p.Val
+	}
+
+	// shrink if error resulted in fewer new results being returned
+	if len(fromnew) > 0 && len(newresults) < len(fromnew) {
+		if len(newresults) > 0 {
+			points = points[:fromnew[len(newresults)-1]+1]
+		} else {
+			points = points[:fromnew[0]]
+		}
+	}
+
+	return points, n, err
+}
+
+func (ev *SQLCacheEvaler) lookup(p Point) (val float64, ok bool, err error) {
+	h := hashPoint(p)
+	row := ev.db.QueryRow(`SELECT val FROM eval_cache WHERE hash = ?`, h[:])
+	switch err = row.Scan(&val); err {
+	case nil:
+		return val, true, nil
+	case sql.ErrNoRows:
+		return 0, false, nil
+	default:
+		return 0, false, err
+	}
+}
+
+func (ev *SQLCacheEvaler) store(points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := ev.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO eval_cache (hash, dim, pos, val, wall_time)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	now := float64(time.Now().Unix())
+	for _, p := range points {
+		h := hashPoint(p)
+		if _, err := stmt.Exec(h[:], p.Len(), posBlob(p), p.Val, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func posBlob(p Point) []byte {
+	data := make([]byte, p.Len()*8)
+	for i := 0; i < p.Len(); i++ {
+		binary.BigEndian.PutUint64(data[i*8:], math.Float64bits(p.At(i)))
+	}
+	return data
+}
+
+// Resume adds DB-backed resumability to a Solver: Run persists one row per
+// iteration to an iterations table in DB, and -- if that table already has
+// rows from a prior run when Run starts -- seeds the search from the best K
+// points in DB's eval_cache instead of relying solely on the caller's
+// initial point.
+type Resume struct {
+	DB *sql.DB
+	// K is the number of cached points to seed from on a resumed run. Zero
+	// defaults to 1.
+	K int
+}
+
+// ensureTable creates the iterations bookkeeping table if it doesn't
+// already exist.
+func (r *Resume) ensureTable() error {
+	_, err := r.DB.Exec(`
+		CREATE TABLE IF NOT EXISTS iterations (
+			iter      INTEGER PRIMARY KEY,
+			best_val  REAL NOT NULL,
+			wall_time REAL NOT NULL
+		)
+	`)
+	return err
+}
+
+// seed feeds s.Method the best K cached points from a prior run, if the
+// iterations table shows one happened.
+func (r *Resume) seed(s *Solver) error {
+	if err := r.ensureTable(); err != nil {
+		return err
+	}
+
+	var priorRuns int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM iterations`).Scan(&priorRuns); err != nil {
+		return err
+	}
+	if priorRuns == 0 {
+		return nil
+	}
+
+	k := r.K
+	if k <= 0 {
+		k = 1
+	}
+
+	rows, err := r.DB.Query(`SELECT dim, pos, val FROM eval_cache ORDER BY val ASC LIMIT ?`, k)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dim int
+		var blob []byte
+		var val float64
+		if err := rows.Scan(&dim, &blob, &val); err != nil {
+			return err
+		}
+
+		pos := make([]float64, dim)
+		for i := 0; i < dim; i++ {
+			pos[i] = math.Float64frombits(binary.BigEndian.Uint64(blob[i*8:]))
+		}
+		s.Method.AddPoint(NewPoint(pos, val))
+	}
+	return rows.Err()
+}
+
+// record appends a row noting the best point found through iteration iter.
+func (r *Resume) record(iter int, best Point) error {
+	_, err := r.DB.Exec(`INSERT OR REPLACE INTO iterations (iter, best_val, wall_time) VALUES (?, ?, ?)`,
+		iter, best.Val, float64(time.Now().Unix()))
+	return err
+}